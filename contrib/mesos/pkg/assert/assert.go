@@ -0,0 +1,50 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package assertext provides small test assertion helpers that don't fit
+// into the stretchr/testify vocabulary, such as polling assertions for
+// conditions that become true asynchronously.
+package assertext
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// EventuallyTrue polls pred every 10ms until it returns true or timeout
+// elapses, failing the test via t.Errorf if the timeout is reached first.
+// It returns whatever pred last returned so that callers may use it in an
+// `if !assertext.EventuallyTrue(...)` guard.
+func EventuallyTrue(t *testing.T, timeout time.Duration, pred func() bool, msgAndArgs ...interface{}) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if pred() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			msg := "condition not met before timeout"
+			if len(msgAndArgs) > 0 {
+				if format, ok := msgAndArgs[0].(string); ok {
+					msg = fmt.Sprintf(format, msgAndArgs[1:]...)
+				}
+			}
+			t.Errorf("%s", msg)
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
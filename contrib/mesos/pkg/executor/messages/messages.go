@@ -0,0 +1,33 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package messages defines the fixed set of strings that flow between the
+// scheduler and the executor as TaskStatus.Message payloads, so that both
+// sides can match on them without sharing magic strings.
+package messages
+
+const (
+	// CreateBindingFailure is reported by the executor when it could not
+	// bind its pod to the apiserver before starting it.
+	CreateBindingFailure = "failed to bind pod to apiserver"
+
+	// TaskLost is reported by the scheduler to the executor when it
+	// believes a task that the executor may still be running has been lost.
+	TaskLost = "task lost"
+
+	// Kamikaze instructs the executor to shut itself down.
+	Kamikaze = "die"
+)
@@ -0,0 +1,109 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package queue implements a small FIFO store, keyed by a caller-supplied
+// unique ID, that the scheduler plugin uses to hold pods that are waiting
+// to be matched against an offer.
+package queue
+
+import "sync"
+
+// ReplacementPolicy controls what Add does when an item with the same UID
+// already exists in the FIFO.
+type ReplacementPolicy int
+
+const (
+	// KeepExisting leaves a pre-existing item (and its position) untouched.
+	KeepExisting ReplacementPolicy = iota
+	// ReplaceExisting overwrites a pre-existing item in place.
+	ReplaceExisting
+)
+
+// UniqueID is implemented by anything that can be stored in a FIFO.
+type UniqueID interface {
+	GetUID() string
+}
+
+// FIFO is a UID-keyed, insertion-ordered store.
+type FIFO interface {
+	Add(item UniqueID, rp ReplacementPolicy)
+	Get(uid string) (UniqueID, bool)
+	Delete(uid string)
+	List() []UniqueID
+}
+
+// historicalFIFO is the default, in-memory FIFO implementation.
+type historicalFIFO struct {
+	lock  sync.Mutex
+	items map[string]UniqueID
+	order []string
+}
+
+// New returns an empty FIFO.
+func New() FIFO {
+	return &historicalFIFO{
+		items: map[string]UniqueID{},
+	}
+}
+
+func (f *historicalFIFO) Add(item UniqueID, rp ReplacementPolicy) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	uid := item.GetUID()
+	if _, exists := f.items[uid]; exists && rp == KeepExisting {
+		return
+	}
+	if _, exists := f.items[uid]; !exists {
+		f.order = append(f.order, uid)
+	}
+	f.items[uid] = item
+}
+
+func (f *historicalFIFO) Get(uid string) (UniqueID, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	item, ok := f.items[uid]
+	return item, ok
+}
+
+func (f *historicalFIFO) Delete(uid string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if _, exists := f.items[uid]; !exists {
+		return
+	}
+	delete(f.items, uid)
+	for i, id := range f.order {
+		if id == uid {
+			f.order = append(f.order[:i], f.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (f *historicalFIFO) List() []UniqueID {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	list := make([]UniqueID, 0, len(f.order))
+	for _, uid := range f.order {
+		list = append(list, f.items[uid])
+	}
+	return list
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config holds the tunables for the Mesos scheduler that are not
+// specific to any one Mesos framework registration.
+package config
+
+import "time"
+
+// Config holds scheduler-wide timing and retry parameters.
+type Config struct {
+	// OfferTTL is how long an unused resource offer is kept around before
+	// it is declined back to Mesos.
+	OfferTTL time.Duration
+
+	// ListenerDelay is the interval between offer-registry housekeeping
+	// passes that expire offers older than OfferTTL.
+	ListenerDelay time.Duration
+}
+
+// CreateDefaultConfig returns the configuration used when none is supplied
+// explicitly, e.g. by most tests.
+func CreateDefaultConfig() *Config {
+	return &Config{
+		OfferTTL:      5 * time.Second,
+		ListenerDelay: 1 * time.Second,
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package controller defines the event reasons that the scheduler plugin
+// reports against a pod via the Kubernetes event recorder. They are
+// collected here, rather than left as string literals scattered across the
+// plugin and its tests, so that the two can't drift apart.
+package controller
+
+const (
+	// Scheduled is reported once a pod has been successfully bound to an
+	// offer and its launch has been sent to Mesos.
+	Scheduled = "scheduled"
+
+	// FailedScheduling is reported when a pod could not be matched against
+	// any currently available offer.
+	FailedScheduling = "failedScheduling"
+
+	// NodeNotReady is reported instead of FailedScheduling when the only
+	// offers seen for a pod were declined because their host has no
+	// registered Node yet.
+	NodeNotReady = "NodeNotReady"
+
+	// FailedBinding is reported when the executor could not bind a pod to
+	// the apiserver before starting it.
+	FailedBinding = "failedBinding"
+
+	// Killed is reported once a launched task has been killed, e.g. in
+	// response to the backing pod being deleted, and also when Mesos
+	// reports a launched task as lost.
+	Killed = "killed"
+
+	// Reconciled is reported when the scheduler has reconciled a task's
+	// state against the apiserver after an unexpected terminal status.
+	Reconciled = "reconciled"
+)
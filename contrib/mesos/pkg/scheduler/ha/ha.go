@@ -0,0 +1,194 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ha drives the lifecycle of the scheduler process across leader
+// election: it owns the Mesos driver, starts it once this process has been
+// elected master, and tears it down on termination or failover.
+package ha
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/gogo/protobuf/proto"
+	log "github.com/golang/glog"
+	mesos "github.com/mesos/mesos-go/mesosproto"
+	util "github.com/mesos/mesos-go/mesosutil"
+	bindings "github.com/mesos/mesos-go/scheduler"
+	"github.com/mesos/mesos-go/upid"
+)
+
+// DriverFactory builds the Mesos scheduler driver used for a single
+// election term. It is invoked once per Elect call so that a fresh driver
+// (and thus a fresh connection to the master) is used on every term.
+type DriverFactory func() (bindings.SchedulerDriver, error)
+
+// SchedulerProcess coordinates the framework's participation in an HA
+// election: only the elected process is allowed to start its Mesos driver.
+type SchedulerProcess struct {
+	framework bindings.Scheduler
+
+	lock      sync.Mutex
+	terminate chan struct{}
+	elected   chan struct{}
+	master    chan string
+
+	driver     bindings.SchedulerDriver
+	masterInfo *mesos.MasterInfo
+}
+
+// New returns a SchedulerProcess that will drive framework once elected.
+func New(framework bindings.Scheduler) *SchedulerProcess {
+	return &SchedulerProcess{
+		framework: framework,
+		terminate: make(chan struct{}),
+		elected:   make(chan struct{}),
+		master:    make(chan string, 1),
+	}
+}
+
+// Terminal is closed when the scheduler process is ending, regardless of
+// whether this process ever won an election.
+func (s *SchedulerProcess) Terminal() <-chan struct{} {
+	return s.terminate
+}
+
+// Elected is closed the moment this process wins an election and its
+// driver has been started.
+func (s *SchedulerProcess) Elected() <-chan struct{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.elected
+}
+
+// Master streams the UPID (id@host:port) of whichever Mesos master this process
+// believes is currently leading, most recent value last. Callers should
+// re-fetch this channel (rather than caching the one returned before the
+// current term) after each failover, since End resets it for the next term.
+func (s *SchedulerProcess) Master() <-chan string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.master
+}
+
+// Elect begins (or re-begins, on failover) this process' bid for
+// leadership, given leader, the UPID of the master a detector has most
+// recently found. In production leader arrives via a detector's
+// leader-change callback; Elect resolves it to a MasterInfo, starts the
+// driver built by driverFactory, and publishes the master's UPID on
+// Master(). The resolved MasterInfo is available from MasterInfo() for the
+// framework's Registered/Reregistered callbacks; Elect does not invoke
+// those callbacks itself.
+func (s *SchedulerProcess) Elect(driverFactory DriverFactory, leader *upid.UPID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.masterInfo = MasterInfoFromUPID(leader)
+
+	driver, err := driverFactory()
+	if err != nil {
+		log.Errorf("failed to create scheduler driver: %v", err)
+		return
+	}
+	s.driver = driver
+
+	if _, err := driver.Start(); err != nil {
+		log.Errorf("failed to start scheduler driver: %v", err)
+		return
+	}
+
+	if leader != nil {
+		s.master <- leader.String()
+	}
+	close(s.elected)
+}
+
+// MasterInfo returns the MasterInfo resolved from the leader UPID passed to
+// the most recent Elect call, or nil if none has resolved successfully.
+func (s *SchedulerProcess) MasterInfo() *mesos.MasterInfo {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.masterInfo
+}
+
+// End tears down the current term, stopping the driver (if any) and
+// closing Terminal(). It also resets the elected/master channels so that a
+// subsequent Elect call (this process regaining leadership after an HA
+// failover) can publish again instead of panicking on an already-closed
+// elected channel or blocking forever on a master channel nothing drains.
+func (s *SchedulerProcess) End() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.driver != nil {
+		s.driver.Stop(false)
+		s.driver = nil
+	}
+	s.elected = make(chan struct{})
+	s.master = make(chan string, 1)
+	select {
+	case <-s.terminate:
+		// already closed
+	default:
+		close(s.terminate)
+	}
+}
+
+// MasterInfoFromUPID resolves pid, the UPID of a Mesos master as reported
+// by a leader detector, into the MasterInfo that Registered/Reregistered
+// expect. It returns nil, logging why, if pid is nil, its port can't be
+// parsed, or its host doesn't resolve to an IPv4 address.
+func MasterInfoFromUPID(pid *upid.UPID) *mesos.MasterInfo {
+	if pid == nil {
+		return nil
+	}
+
+	port, err := strconv.Atoi(pid.Port)
+	if err != nil {
+		log.Errorf("failed to parse port from mesos master UPID %v: %v", pid, err)
+		return nil
+	}
+
+	ip, err := ipv4From(pid.Host)
+	if err != nil {
+		log.Errorf("failed to resolve an ipv4 address for mesos master UPID %v: %v", pid, err)
+		return nil
+	}
+
+	mi := util.NewMasterInfo(pid.ID, binary.BigEndian.Uint32(ip), uint32(port))
+	mi.Pid = proto.String(pid.String())
+	if pid.Host != "" {
+		mi.Hostname = proto.String(pid.Host)
+	}
+	return mi
+}
+
+// ipv4From resolves host and returns its first IPv4 address.
+func ipv4From(host string) (net.IP, error) {
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4, nil
+		}
+	}
+	return nil, fmt.Errorf("no ipv4 address found for host %v", host)
+}
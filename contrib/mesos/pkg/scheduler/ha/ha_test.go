@@ -0,0 +1,100 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ha
+
+import (
+	"testing"
+	"time"
+
+	mesos "github.com/mesos/mesos-go/mesosproto"
+	bindings "github.com/mesos/mesos-go/scheduler"
+	"github.com/mesos/mesos-go/upid"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubDriver is a no-op bindings.SchedulerDriver that always succeeds; it
+// exists so Elect has something to Start/Stop without pulling in a mock
+// framework for every term of a re-election test.
+type stubDriver struct{}
+
+func (stubDriver) Start() (mesos.Status, error) { return mesos.Status_DRIVER_RUNNING, nil }
+func (stubDriver) Stop(bool) (mesos.Status, error) {
+	return mesos.Status_DRIVER_STOPPED, nil
+}
+func (stubDriver) Abort() (mesos.Status, error) { return mesos.Status_DRIVER_ABORTED, nil }
+func (stubDriver) Join() (mesos.Status, error)  { return mesos.Status_DRIVER_STOPPED, nil }
+func (stubDriver) Run() (mesos.Status, error)   { return mesos.Status_DRIVER_RUNNING, nil }
+func (stubDriver) RequestResources([]*mesos.Request) (mesos.Status, error) {
+	return mesos.Status_DRIVER_RUNNING, nil
+}
+func (stubDriver) LaunchTasks([]*mesos.OfferID, []*mesos.TaskInfo, *mesos.Filters) (mesos.Status, error) {
+	return mesos.Status_DRIVER_RUNNING, nil
+}
+func (stubDriver) KillTask(*mesos.TaskID) (mesos.Status, error) {
+	return mesos.Status_DRIVER_RUNNING, nil
+}
+func (stubDriver) DeclineOffer(*mesos.OfferID, *mesos.Filters) (mesos.Status, error) {
+	return mesos.Status_DRIVER_RUNNING, nil
+}
+func (stubDriver) ReviveOffers() (mesos.Status, error) { return mesos.Status_DRIVER_RUNNING, nil }
+func (stubDriver) SendFrameworkMessage(*mesos.ExecutorID, *mesos.SlaveID, string) (mesos.Status, error) {
+	return mesos.Status_DRIVER_RUNNING, nil
+}
+func (stubDriver) ReconcileTasks([]*mesos.TaskStatus) (mesos.Status, error) {
+	return mesos.Status_DRIVER_RUNNING, nil
+}
+
+var _ bindings.SchedulerDriver = stubDriver{}
+
+func stubDriverFactory() (bindings.SchedulerDriver, error) {
+	return stubDriver{}, nil
+}
+
+// waitElected blocks on elected closing, failing the test instead of
+// hanging forever if Elect deadlocks.
+func waitElected(t *testing.T, elected <-chan struct{}) {
+	select {
+	case <-elected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting to be elected; Elect may be deadlocked")
+	}
+}
+
+// TestSchedulerProcess_Reelect drives a SchedulerProcess through the
+// Elect/End/Elect cycle that an HA failover (losing leadership and later
+// regaining it) produces, and would catch the Elect panicking on an
+// already-closed elected channel or deadlocking on an already-full master
+// channel on the second term.
+func TestSchedulerProcess_Reelect(t *testing.T) {
+	sp := New(nil)
+
+	leader1, err := upid.Parse("master-one@10.0.0.1:5050")
+	assert.NoError(t, err)
+	elected := sp.Elected()
+	go sp.Elect(stubDriverFactory, leader1)
+	waitElected(t, elected)
+	assert.Equal(t, "10.0.0.1", sp.MasterInfo().GetHostname())
+
+	sp.End()
+
+	leader2, err := upid.Parse("master-two@10.0.0.2:5050")
+	assert.NoError(t, err)
+	elected = sp.Elected()
+	go sp.Elect(stubDriverFactory, leader2)
+	waitElected(t, elected)
+	assert.Equal(t, "10.0.0.2", sp.MasterInfo().GetHostname())
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/testapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+
+	schedcfg "github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/config"
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/ha"
+	mesos "github.com/mesos/mesos-go/mesosproto"
+	util "github.com/mesos/mesos-go/mesosutil"
+	bindings "github.com/mesos/mesos-go/scheduler"
+	"github.com/mesos/mesos-go/upid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testMasterUPID is the leader address a detector would hand to Elect in
+// production; driving election through it (rather than a hand-built
+// MasterInfo) exercises ha.MasterInfoFromUPID's resolution path.
+const testMasterUPID = "master-id@127.0.0.1:5050"
+
+// lifecycleTest owns the mock apiserver, scheduler and plugin wiring shared
+// by the plugin's end-to-end lifecycle scenarios, so that each scenario can
+// focus on the behavior it's exercising instead of repeating setup.
+type lifecycleTest struct {
+	t                *testing.T
+	assert           *EventAssertions
+	apiServer        *TestServer
+	podListWatch     *MockPodsListWatch
+	executor         *mesos.ExecutorInfo
+	scheduler        *KubernetesScheduler
+	schedulerProcess *ha.SchedulerProcess
+	plugin           *Plugin
+	mockDriver       *joinableDriver
+	eventObs         *EventObserver
+}
+
+// newLifecycleTest builds the scheduler and its mock apiserver, but does not
+// yet elect it or start the plugin; call Start for that.
+func newLifecycleTest(t *testing.T) *lifecycleTest {
+	podListWatch := NewMockPodsListWatch(api.PodList{})
+	apiServer := NewTestServer(t, api.NamespaceDefault, podListWatch)
+
+	executor := util.NewExecutorInfo(
+		util.NewExecutorID("executor-id"),
+		util.NewCommandInfo("executor-cmd"),
+	)
+	executor.Data = []byte{0, 1, 2}
+
+	sched := New(Config{
+		Executor:     executor,
+		Client:       client.NewOrDie(&client.Config{Host: apiServer.server.URL, Version: testapi.Version()}),
+		ScheduleFunc: FCFSScheduleFunc,
+		Schedcfg:     *schedcfg.CreateDefaultConfig(),
+	})
+
+	return &lifecycleTest{
+		t:                t,
+		assert:           &EventAssertions{*assert.New(t)},
+		apiServer:        apiServer,
+		podListWatch:     podListWatch,
+		executor:         executor,
+		scheduler:        sched,
+		schedulerProcess: ha.New(sched),
+	}
+}
+
+// Start wires up the plugin, wins the (sole) election with a mock driver and
+// blocks until the scheduler has registered, returning the channel on which
+// every launched task is reported.
+func (lt *lifecycleTest) Start() chan *mesos.TaskInfo {
+	assert := lt.assert
+
+	c := lt.scheduler.NewPluginConfig(lt.schedulerProcess.Terminal(), http.DefaultServeMux, &lt.podListWatch.ListWatch)
+	assert.NotNil(c)
+
+	lt.eventObs = NewEventObserver()
+	c.Recorder = lt.eventObs
+
+	lt.plugin = NewPlugin(c)
+	assert.NotNil(lt.plugin)
+
+	lt.plugin.Run(lt.schedulerProcess.Terminal())
+
+	err := lt.scheduler.Init(lt.schedulerProcess.Master(), lt.plugin, http.DefaultServeMux)
+	assert.NoError(err)
+
+	lt.mockDriver = &joinableDriver{}
+	lt.mockDriver.On("Start").Return(mesos.Status_DRIVER_RUNNING, nil).Once()
+	started := lt.mockDriver.Upon()
+
+	mAny := mock.AnythingOfType
+	lt.mockDriver.On("ReconcileTasks", mAny("[]*mesosproto.TaskStatus")).Return(mesos.Status_DRIVER_RUNNING, nil)
+	lt.mockDriver.On("SendFrameworkMessage", mAny("*mesosproto.ExecutorID"), mAny("*mesosproto.SlaveID"), mAny("string")).
+		Return(mesos.Status_DRIVER_RUNNING, nil)
+
+	launchedTasks := make(chan *mesos.TaskInfo, 1)
+	lt.mockDriver.On("LaunchTasks", mAny("[]*mesosproto.OfferID"), mAny("[]*mesosproto.TaskInfo"), mAny("*mesosproto.Filters")).
+		Return(mesos.Status_DRIVER_RUNNING, nil).Run(func(args mock.Arguments) {
+		taskInfos := args.Get(1).([]*mesos.TaskInfo)
+		assert.Equal(1, len(taskInfos))
+		launchedTasks <- taskInfos[0]
+	})
+	lt.mockDriver.On("DeclineOffer", mAny("*mesosproto.OfferID"), mAny("*mesosproto.Filters")).
+		Return(mesos.Status_DRIVER_RUNNING, nil)
+	// End stops the driver of the current term
+	lt.mockDriver.On("Stop", false).Return(mesos.Status_DRIVER_STOPPED, nil)
+
+	driverFactory := ha.DriverFactory(func() (bindings.SchedulerDriver, error) {
+		return lt.mockDriver, nil
+	})
+	leader, err := upid.Parse(testMasterUPID)
+	assert.NoError(err)
+	lt.schedulerProcess.Elect(driverFactory, leader)
+	elected := lt.schedulerProcess.Elected()
+
+	// driver will be started
+	<-started
+
+	// tell scheduler to be registered, using the MasterInfo that Elect
+	// resolved from the leader's UPID
+	lt.scheduler.Registered(
+		lt.mockDriver,
+		util.NewFrameworkID("kubernetes-id"),
+		lt.schedulerProcess.MasterInfo(),
+	)
+
+	// wait for being elected
+	<-elected
+
+	return launchedTasks
+}
+
+// End tears down the election/driver for this term.
+func (lt *lifecycleTest) End() {
+	lt.schedulerProcess.End()
+}
+
+// Close shuts down the mock apiserver. Safe to call even if Start was never
+// called.
+func (lt *lifecycleTest) Close() {
+	lt.apiServer.server.Close()
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	log "github.com/golang/glog"
+)
+
+// nodeRegistrationPollInterval is how often we re-list /api/v1/nodes while
+// watching for new registrations.
+const nodeRegistrationPollInterval = 1 * time.Second
+
+// nodeStore holds the set of hostnames for which a Kubernetes Node object
+// currently exists. Offers are only considered for hosts in this set: a
+// Mesos slave whose kubelet has not yet registered a Node has nowhere for
+// a pod binding to land.
+type nodeStore struct {
+	lock       sync.RWMutex
+	registered map[string]bool
+}
+
+func newNodeStore() *nodeStore {
+	return &nodeStore{
+		registered: map[string]bool{},
+	}
+}
+
+func (n *nodeStore) isRegistered(hostname string) bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.registered[hostname]
+}
+
+func (n *nodeStore) set(hostnames map[string]bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.registered = hostnames
+}
+
+// watch starts a goroutine that periodically lists /api/v1/nodes and
+// refreshes the set of registered hostnames. It never terminates; the
+// scheduler is expected to live for the lifetime of the process.
+func (n *nodeStore) watch(c *client.Client) {
+	go func() {
+		for {
+			nodes, err := c.Nodes().List()
+			if err != nil {
+				log.Errorf("failed to list nodes: %v", err)
+			} else {
+				hostnames := make(map[string]bool, len(nodes.Items))
+				for _, node := range nodes.Items {
+					hostnames[node.Name] = true
+				}
+				n.set(hostnames)
+			}
+			time.Sleep(nodeRegistrationPollInterval)
+		}
+	}()
+}
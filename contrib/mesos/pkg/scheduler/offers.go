@@ -0,0 +1,128 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	mesos "github.com/mesos/mesos-go/mesosproto"
+	bindings "github.com/mesos/mesos-go/scheduler"
+)
+
+// offerEntry pairs an offer with the time it was added to the registry, so
+// that expireOlderThan can tell how long it has been sitting unused.
+type offerEntry struct {
+	offer   *mesos.Offer
+	arrived time.Time
+}
+
+// offerRegistry holds resource offers that are usable right now, i.e. that
+// came from a host with a registered Node. It also remembers whether the
+// most recent batch of offers was declined solely because their hosts
+// weren't registered yet, so that the scheduling loop can report a more
+// specific failedScheduling reason to the user.
+//
+// declinedForUnregisteredNode is scheduler-wide, not per-pod or per-offer:
+// it only attributes the right reason to the right pod because scheduleLoop
+// evaluates exactly one queued pod against one offer batch per tick. If
+// scheduling is ever changed to consider several pods per tick, this flag
+// will need to become per-pod (or the reason threaded through from the
+// specific offers evaluated for that pod) to stay accurate.
+type offerRegistry struct {
+	lock                        sync.Mutex
+	available                   map[string]offerEntry
+	declinedForUnregisteredNode bool
+}
+
+func newOfferRegistry() *offerRegistry {
+	return &offerRegistry{
+		available: map[string]offerEntry{},
+	}
+}
+
+func (r *offerRegistry) add(offer *mesos.Offer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.available[offer.Id.GetValue()] = offerEntry{offer: offer, arrived: time.Now()}
+	r.declinedForUnregisteredNode = false
+}
+
+func (r *offerRegistry) remove(id string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.available, id)
+}
+
+// noteUnregisteredNodeDecline records that an offer was just declined
+// because its host has no registered Node yet.
+func (r *offerRegistry) noteUnregisteredNodeDecline() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.available) == 0 {
+		r.declinedForUnregisteredNode = true
+	}
+}
+
+// take removes and returns every currently available offer. Callers that
+// don't consume every offer they get back (e.g. a scheduleFunc only ever
+// launches one) must decline the rest themselves, since take forgets them
+// for good.
+func (r *offerRegistry) take() []*mesos.Offer {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	offers := make([]*mesos.Offer, 0, len(r.available))
+	for id, entry := range r.available {
+		offers = append(offers, entry.offer)
+		delete(r.available, id)
+	}
+	return offers
+}
+
+// expireOlderThan declines back to Mesos, and forgets, every available
+// offer that has been sitting unused for longer than ttl. driver may be
+// nil (e.g. before the scheduler has registered), in which case offers are
+// just forgotten without being declined.
+func (r *offerRegistry) expireOlderThan(ttl time.Duration, driver bindings.SchedulerDriver) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	for id, entry := range r.available {
+		if now.Sub(entry.arrived) < ttl {
+			continue
+		}
+		delete(r.available, id)
+		if driver != nil {
+			driver.DeclineOffer(entry.offer.Id, &mesos.Filters{})
+		}
+	}
+}
+
+// lastDeclineWasUnregisteredNode reports whether the reason no offers are
+// available right now is that the only offers seen so far were declined
+// for lacking a registered Node.
+func (r *offerRegistry) lastDeclineWasUnregisteredNode() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return len(r.available) == 0 && r.declinedForUnregisteredNode
+}
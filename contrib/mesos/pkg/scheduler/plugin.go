@@ -0,0 +1,252 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/cache"
+
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/controller"
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/podtask"
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/queue"
+	log "github.com/golang/glog"
+	mesos "github.com/mesos/mesos-go/mesosproto"
+)
+
+// schedulingLoopInterval is how often the Plugin tries to match a queued
+// pod against whatever offers the scheduler currently has on hand.
+const schedulingLoopInterval = 50 * time.Millisecond
+
+var noSuchPodErr = errors.New("no such pod exists")
+var badPodNamingErr = errors.New("pod has no namespace/name to key on")
+
+// schedulerInterface is the slice of KubernetesScheduler that the plugin's
+// queuer and deleter need; it exists so that tests can substitute a mock.
+type schedulerInterface interface {
+	tasks() podtask.Registry
+	killTask(taskID string) error
+}
+
+// Pod adapts an *api.Pod so it can live in a queue.FIFO, keyed by
+// namespace/name.
+type Pod struct {
+	*api.Pod
+}
+
+// GetUID implements queue.UniqueID.
+func (p *Pod) GetUID() string {
+	return fmt.Sprintf("%v/%v", p.Namespace, p.Name)
+}
+
+// queuer owns the FIFO of pods that are waiting for a matching offer.
+type queuer struct {
+	podQueue queue.FIFO
+	podStore cache.Store
+}
+
+func newQueuer(podStore cache.Store) *queuer {
+	return &queuer{
+		podQueue: queue.New(),
+		podStore: podStore,
+	}
+}
+
+func (q *queuer) enqueue(pod *Pod) {
+	q.podQueue.Add(pod, queue.ReplaceExisting)
+}
+
+func (q *queuer) dequeue() (*Pod, bool) {
+	for _, item := range q.podQueue.List() {
+		return item.(*Pod), true
+	}
+	return nil, false
+}
+
+// deleter removes a deleted pod's task (if any) from the scheduler,
+// killing it in Mesos if it had already been launched.
+type deleter struct {
+	api schedulerInterface
+	qr  *queuer
+}
+
+func (d *deleter) deleteOne(pod *Pod) error {
+	if pod.Namespace == "" || pod.Name == "" {
+		return badPodNamingErr
+	}
+
+	podID := fmt.Sprintf("%v/%v", pod.Namespace, pod.Name)
+	task, ok := d.api.tasks().ForPod(podID)
+	if !ok {
+		return noSuchPodErr
+	}
+
+	d.qr.podQueue.Delete(podID)
+
+	if task.State == podtask.Launched || task.State == podtask.Running {
+		return d.api.killTask(task.ID)
+	}
+	return nil
+}
+
+// Plugin is the scheduler.Plugin implementation that binds the
+// KubernetesScheduler to a particular pod ListWatch and mux.
+type Plugin struct {
+	config  *PluginConfig
+	qr      *queuer
+	deleter *deleter
+}
+
+// NewPlugin constructs a Plugin from config. config.Scheduler may be nil in
+// tests that only exercise the plugin's queue/delete bookkeeping.
+func NewPlugin(config *PluginConfig) *Plugin {
+	qr := newQueuer(nil)
+	p := &Plugin{
+		config: config,
+		qr:     qr,
+	}
+	if config.Scheduler != nil {
+		p.deleter = &deleter{api: config.Scheduler, qr: qr}
+	}
+	return p
+}
+
+// Run starts the plugin's pod watch and scheduling loop. It returns
+// immediately; both goroutines stop once terminate is closed.
+func (p *Plugin) Run(terminate <-chan struct{}) {
+	if p.config == nil || p.config.PodsListWatch == nil {
+		return
+	}
+
+	go p.watchPods(terminate)
+	go p.scheduleLoop(terminate)
+}
+
+// watchPods funnels pod add/update/delete notifications from the
+// apiserver's watch stream into the queuer and deleter.
+func (p *Plugin) watchPods(terminate <-chan struct{}) {
+	w, err := p.config.PodsListWatch.Watch("0")
+	if err != nil {
+		log.Errorf("failed to watch pods: %v", err)
+		return
+	}
+	for {
+		select {
+		case <-terminate:
+			return
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			pod, ok := event.Object.(*api.Pod)
+			if !ok {
+				continue
+			}
+			switch event.Type {
+			case "ADDED", "MODIFIED":
+				p.qr.enqueue(&Pod{Pod: pod})
+			case "DELETED":
+				if p.deleter != nil {
+					if err := p.deleter.deleteOne(&Pod{Pod: pod}); err != nil && err != noSuchPodErr {
+						log.Errorf("failed to delete pod %v/%v: %v", pod.Namespace, pod.Name, err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// scheduleLoop repeatedly tries to match the head of the pod queue against
+// whatever offers the scheduler currently has, reporting a failedScheduling
+// event (or the more specific NodeNotReady) when it can't.
+func (p *Plugin) scheduleLoop(terminate <-chan struct{}) {
+	k := p.config.Scheduler
+	if k == nil {
+		return
+	}
+	ticker := time.NewTicker(schedulingLoopInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-terminate:
+			return
+		case <-ticker.C:
+			pod, ok := p.qr.dequeue()
+			if !ok {
+				continue
+			}
+
+			offers := k.offers.take()
+			if len(offers) == 0 {
+				reason := controller.FailedScheduling
+				if k.offers.lastDeclineWasUnregisteredNode() {
+					reason = controller.NodeNotReady
+				}
+				p.config.Recorder.Eventf(pod.Pod, reason, "no offers are currently available")
+				continue
+			}
+
+			task := podtask.New(api.NewDefaultContext(), fmt.Sprintf("%v-%v", pod.Namespace, pod.Name), *pod.Pod, k.executor)
+			offer, err := k.scheduleFunc(offers, task)
+			if err != nil {
+				p.config.Recorder.Eventf(pod.Pod, controller.FailedScheduling, "%v", err)
+				declineOffers(k, offers...)
+				continue
+			}
+			declineOffers(k, unchosen(offers, offer)...)
+
+			taskInfo := buildTaskInfo(task, offer)
+			if k.driver != nil {
+				k.driver.LaunchTasks([]*mesos.OfferID{offer.Id}, []*mesos.TaskInfo{taskInfo}, &mesos.Filters{})
+			}
+			k.tasksR.Register(task)
+			task.Set(podtask.Launched)
+			k.tasksR.Update(task)
+
+			p.qr.podQueue.Delete(pod.GetUID())
+			p.config.Recorder.Eventf(pod.Pod, controller.Scheduled, "Successfully assigned %v to %v", pod.Name, offer.GetHostname())
+		}
+	}
+}
+
+// unchosen returns every offer in offers other than chosen, by offer ID.
+func unchosen(offers []*mesos.Offer, chosen *mesos.Offer) []*mesos.Offer {
+	rest := make([]*mesos.Offer, 0, len(offers)-1)
+	for _, offer := range offers {
+		if offer.Id.GetValue() != chosen.Id.GetValue() {
+			rest = append(rest, offer)
+		}
+	}
+	return rest
+}
+
+// declineOffers hands every one of offers back to Mesos. take() removes an
+// entire batch from the registry at once, but a single scheduling attempt
+// only ever consumes (at most) one of them; anything left over must be
+// declined here or Mesos would otherwise consider it outstanding forever.
+func declineOffers(k *KubernetesScheduler, offers ...*mesos.Offer) {
+	if k.driver == nil {
+		return
+	}
+	for _, offer := range offers {
+		k.driver.DeclineOffer(offer.Id, &mesos.Filters{})
+	}
+}
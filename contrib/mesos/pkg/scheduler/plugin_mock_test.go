@@ -0,0 +1,142 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/podtask"
+	mesos "github.com/mesos/mesos-go/mesosproto"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockScheduler is a testify mock of schedulerInterface, used to unit test
+// the plugin's deleter/queuer in isolation from the real scheduler.
+type MockScheduler struct {
+	mock.Mock
+}
+
+func (m *MockScheduler) tasks() podtask.Registry {
+	args := m.Called()
+	return args.Get(0).(podtask.Registry)
+}
+
+func (m *MockScheduler) killTask(taskID string) error {
+	args := m.Called(taskID)
+	return args.Error(0)
+}
+
+// MockSchedulerDriver is a testify mock of bindings.SchedulerDriver.
+type MockSchedulerDriver struct {
+	mock.Mock
+
+	lock    sync.Mutex
+	waiters []chan struct{}
+}
+
+// Upon returns a channel that is closed the next time any driver method is
+// invoked; it lets a test block until some expected driver interaction has
+// happened without resorting to time.Sleep.
+func (m *MockSchedulerDriver) Upon() <-chan struct{} {
+	ch := make(chan struct{})
+	m.lock.Lock()
+	m.waiters = append(m.waiters, ch)
+	m.lock.Unlock()
+	return ch
+}
+
+func (m *MockSchedulerDriver) signal() {
+	m.lock.Lock()
+	waiters := m.waiters
+	m.waiters = nil
+	m.lock.Unlock()
+	for _, w := range waiters {
+		close(w)
+	}
+}
+
+func (m *MockSchedulerDriver) Start() (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called()
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) Stop(failover bool) (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called(failover)
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) Abort() (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called()
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) Join() (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called()
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) Run() (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called()
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) RequestResources(requests []*mesos.Request) (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called(requests)
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) LaunchTasks(offerIds []*mesos.OfferID, tasks []*mesos.TaskInfo, filters *mesos.Filters) (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called(offerIds, tasks, filters)
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) KillTask(taskID *mesos.TaskID) (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called(taskID)
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) DeclineOffer(offerID *mesos.OfferID, filters *mesos.Filters) (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called(offerID, filters)
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) ReviveOffers() (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called()
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) SendFrameworkMessage(executorID *mesos.ExecutorID, slaveID *mesos.SlaveID, data string) (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called(executorID, slaveID, data)
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
+
+func (m *MockSchedulerDriver) ReconcileTasks(statuses []*mesos.TaskStatus) (mesos.Status, error) {
+	defer m.signal()
+	args := m.Called(statuses)
+	return args.Get(0).(mesos.Status), args.Error(1)
+}
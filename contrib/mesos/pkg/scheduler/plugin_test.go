@@ -26,7 +26,6 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/testapi"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/cache"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
 	kutil "github.com/GoogleCloudPlatform/kubernetes/pkg/util"
@@ -35,30 +34,46 @@ import (
 	assertext "github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/assert"
 	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/executor/messages"
 	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/queue"
-	schedcfg "github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/config"
-	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/ha"
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/controller"
 	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/podtask"
 	log "github.com/golang/glog"
 	mesos "github.com/mesos/mesos-go/mesosproto"
 	util "github.com/mesos/mesos-go/mesosutil"
-	bindings "github.com/mesos/mesos-go/scheduler"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
 // A apiserver mock which partially mocks the pods API
 type TestServer struct {
-	server *httptest.Server
-	stats  map[string]uint
-	lock   sync.Mutex
+	server        *httptest.Server
+	stats         map[string]uint
+	lock          sync.Mutex
+	nodes         map[string]bool
+	nodeListCount uint
 }
 
 func NewTestServer(t *testing.T, namespace string, mockPodListWatch *MockPodsListWatch) *TestServer {
 	ts := TestServer{
 		stats: map[string]uint{},
+		nodes: map[string]bool{},
 	}
 	mux := http.NewServeMux()
 
+	mux.HandleFunc(testapi.ResourcePath("nodes", "", ""), func(w http.ResponseWriter, r *http.Request) {
+		ts.lock.Lock()
+		ts.nodeListCount++
+		nodes := api.NodeList{}
+		for hostname := range ts.nodes {
+			nodes.Items = append(nodes.Items, api.Node{
+				ObjectMeta: api.ObjectMeta{Name: hostname},
+			})
+		}
+		ts.lock.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(runtime.EncodeOrDie(testapi.Codec(), &nodes)))
+	})
+
 	mux.HandleFunc(testapi.ResourcePath("pods", namespace, ""), func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		pods := mockPodListWatch.Pods()
@@ -102,6 +117,24 @@ func (ts *TestServer) Stats(name string) uint {
 	return ts.stats[name]
 }
 
+// WaitForNode registers a Node for hostname with the mock apiserver and
+// blocks until the scheduler's node watch has polled the apiserver at
+// least twice more. The watch polls one request at a time, so the second
+// poll starting means the first one (which already saw the new Node) has
+// been stored by the scheduler before callers proceed.
+func (ts *TestServer) WaitForNode(t *testing.T, hostname string) {
+	ts.lock.Lock()
+	ts.nodes[hostname] = true
+	before := ts.nodeListCount
+	ts.lock.Unlock()
+
+	assertext.EventuallyTrue(t, 3*time.Second, func() bool {
+		ts.lock.Lock()
+		defer ts.lock.Unlock()
+		return ts.nodeListCount > before+1
+	}, "expected apiserver to be polled for nodes after registering %v", hostname)
+}
+
 // Create mock of pods ListWatch, usually listening on the apiserver pods watch endpoint
 type MockPodsListWatch struct {
 	ListWatch   cache.ListWatch
@@ -370,129 +403,51 @@ func TestPlugin_New(t *testing.T) {
 // and play through the whole life cycle of the plugin while creating pods, deleting
 // and failing them.
 func TestPlugin_LifeCycle(t *testing.T) {
-	t.Skip("disabled due to flakiness; see #10795")
-	assert := &EventAssertions{*assert.New(t)}
-
-	// create a fake pod watch. We use that below to submit new pods to the scheduler
-	podListWatch := NewMockPodsListWatch(api.PodList{})
-
-	// create fake apiserver
-	testApiServer := NewTestServer(t, api.NamespaceDefault, podListWatch)
-	defer testApiServer.server.Close()
-
-	// create executor with some data for static pods if set
-	executor := util.NewExecutorInfo(
-		util.NewExecutorID("executor-id"),
-		util.NewCommandInfo("executor-cmd"),
-	)
-	executor.Data = []byte{0, 1, 2}
-
-	// create scheduler
-	testScheduler := New(Config{
-		Executor:     executor,
-		Client:       client.NewOrDie(&client.Config{Host: testApiServer.server.URL, Version: testapi.Version()}),
-		ScheduleFunc: FCFSScheduleFunc,
-		Schedcfg:     *schedcfg.CreateDefaultConfig(),
-	})
-
-	assert.NotNil(testScheduler.client, "client is nil")
-	assert.NotNil(testScheduler.executor, "executor is nil")
-	assert.NotNil(testScheduler.offers, "offer registry is nil")
-
-	// create scheduler process
-	schedulerProcess := ha.New(testScheduler)
-
-	// get plugin config from it
-	c := testScheduler.NewPluginConfig(schedulerProcess.Terminal(), http.DefaultServeMux, &podListWatch.ListWatch)
-	assert.NotNil(c)
-
-	// make events observable
-	eventObserver := NewEventObserver()
-	c.Recorder = eventObserver
-
-	// create plugin
-	p := NewPlugin(c)
-	assert.NotNil(p)
-
-	// run plugin
-	p.Run(schedulerProcess.Terminal())
-	defer schedulerProcess.End()
-
-	// init scheduler
-	err := testScheduler.Init(schedulerProcess.Master(), p, http.DefaultServeMux)
-	assert.NoError(err)
-
-	// create mock mesos scheduler driver
-	mockDriver := &joinableDriver{}
-	mockDriver.On("Start").Return(mesos.Status_DRIVER_RUNNING, nil).Once()
-	started := mockDriver.Upon()
+	lt := newLifecycleTest(t)
+	defer lt.Close()
+	assert := lt.assert
 
-	mAny := mock.AnythingOfType
-	mockDriver.On("ReconcileTasks", mAny("[]*mesosproto.TaskStatus")).Return(mesos.Status_DRIVER_RUNNING, nil)
-	mockDriver.On("SendFrameworkMessage", mAny("*mesosproto.ExecutorID"), mAny("*mesosproto.SlaveID"), mAny("string")).
-		Return(mesos.Status_DRIVER_RUNNING, nil)
-
-	launchedTasks := make(chan *mesos.TaskInfo, 1)
-	launchTasksCalledFunc := func(args mock.Arguments) {
-		taskInfos := args.Get(1).([]*mesos.TaskInfo)
-		assert.Equal(1, len(taskInfos))
-		launchedTasks <- taskInfos[0]
-	}
-	mockDriver.On("LaunchTasks", mAny("[]*mesosproto.OfferID"), mAny("[]*mesosproto.TaskInfo"), mAny("*mesosproto.Filters")).
-		Return(mesos.Status_DRIVER_RUNNING, nil).Run(launchTasksCalledFunc)
-	mockDriver.On("DeclineOffer", mAny("*mesosproto.OfferID"), mAny("*mesosproto.Filters")).
-		Return(mesos.Status_DRIVER_RUNNING, nil)
-
-	// elect master with mock driver
-	driverFactory := ha.DriverFactory(func() (bindings.SchedulerDriver, error) {
-		return mockDriver, nil
-	})
-	schedulerProcess.Elect(driverFactory)
-	elected := schedulerProcess.Elected()
-
-	// driver will be started
-	<-started
-
-	// tell scheduler to be registered
-	testScheduler.Registered(
-		mockDriver,
-		util.NewFrameworkID("kubernetes-id"),
-		util.NewMasterInfo("master-id", (192<<24)+(168<<16)+(0<<8)+1, 5050),
-	)
-
-	// wait for being elected
-	<-elected
-
-	//TODO(jdef) refactor things above here into a test suite setup of some sort
+	launchedTasks := lt.Start()
+	defer lt.End()
 
 	// fake new, unscheduled pod
 	pod1 := NewTestPod(1)
-	podListWatch.Add(pod1, true) // notify watchers
+	lt.podListWatch.Add(pod1, true) // notify watchers
 
 	// wait for failedScheduling event because there is no offer
-	assert.EventWithReason(eventObserver, "failedScheduling", "failedScheduling event not received")
+	assert.EventWithReason(lt.eventObs, controller.FailedScheduling, "failedScheduling event not received")
 
-	// add some matching offer
+	// offer a host whose Node hasn't registered with the apiserver yet; the
+	// scheduler must decline it rather than bind a pod to a host with no
+	// kubelet-backed Node, and pod1 should see a more specific reason than
+	// the generic failedScheduling above
 	offers1 := []*mesos.Offer{NewTestOffer(1)}
-	testScheduler.ResourceOffers(nil, offers1)
+	lt.scheduler.ResourceOffers(lt.mockDriver, offers1)
+	assert.EventWithReason(lt.eventObs, controller.NodeNotReady, "NodeNotReady event not received")
+
+	// register the Node for h1 and wait for the scheduler to notice
+	lt.apiServer.WaitForNode(t, *offers1[0].Hostname)
+
+	// offer the same host again now that its Node is registered
+	lt.scheduler.ResourceOffers(lt.mockDriver, offers1)
 
 	// and wait for scheduled pod
-	assert.EventWithReason(eventObserver, "scheduled")
+	assert.EventWithReason(lt.eventObs, controller.Scheduled)
 	select {
 	case launchedTask := <-launchedTasks:
 		// report back that the task has been staged, and then started by mesos
-		testScheduler.StatusUpdate(mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_STAGING))
-		testScheduler.StatusUpdate(mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_RUNNING))
+		lt.scheduler.StatusUpdate(lt.mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_STAGING))
+		lt.scheduler.StatusUpdate(lt.mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_RUNNING))
 
 		// check that ExecutorInfo.data has the static pod data
 		assert.Len(launchedTask.Executor.Data, 3)
 
 		// report back that the task has been lost
-		mockDriver.AssertNumberOfCalls(t, "SendFrameworkMessage", 0)
-		testScheduler.StatusUpdate(mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_LOST))
+		lt.mockDriver.AssertNumberOfCalls(t, "SendFrameworkMessage", 0)
+		lt.scheduler.StatusUpdate(lt.mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_LOST))
 
 		// and wait that framework message is sent to executor
-		mockDriver.AssertNumberOfCalls(t, "SendFrameworkMessage", 1)
+		lt.mockDriver.AssertNumberOfCalls(t, "SendFrameworkMessage", 1)
 
 	case <-time.After(5 * time.Second):
 		t.Fatalf("timed out waiting for launchTasks call")
@@ -505,15 +460,15 @@ func TestPlugin_LifeCycle(t *testing.T) {
 
 		// create pod and matching offer
 		pod := NewTestPod(podNum)
-		podListWatch.Add(pod, true) // notify watchers
-		testScheduler.ResourceOffers(mockDriver, offers)
-		assert.EventWithReason(eventObserver, "scheduled")
+		lt.podListWatch.Add(pod, true) // notify watchers
+		lt.scheduler.ResourceOffers(lt.mockDriver, offers)
+		assert.EventWithReason(lt.eventObs, controller.Scheduled)
 
 		// wait for driver.launchTasks call
 		select {
 		case launchedTask := <-launchedTasks:
-			testScheduler.StatusUpdate(mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_STAGING))
-			testScheduler.StatusUpdate(mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_RUNNING))
+			lt.scheduler.StatusUpdate(lt.mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_STAGING))
+			lt.scheduler.StatusUpdate(lt.mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_RUNNING))
 			return pod, launchedTask
 
 		case <-time.After(5 * time.Second):
@@ -525,20 +480,21 @@ func TestPlugin_LifeCycle(t *testing.T) {
 	pod, launchedTask := startPod(offers1)
 
 	// mock drvier.KillTask, should be invoked when a pod is deleted
-	mockDriver.On("KillTask", mAny("*mesosproto.TaskID")).Return(mesos.Status_DRIVER_RUNNING, nil).Run(func(args mock.Arguments) {
+	mAny := mock.AnythingOfType
+	lt.mockDriver.On("KillTask", mAny("*mesosproto.TaskID")).Return(mesos.Status_DRIVER_RUNNING, nil).Run(func(args mock.Arguments) {
 		killedTaskId := *(args.Get(0).(*mesos.TaskID))
 		assert.Equal(*launchedTask.TaskId, killedTaskId, "expected same TaskID as during launch")
 	})
-	killTaskCalled := mockDriver.Upon()
+	killTaskCalled := lt.mockDriver.Upon()
 
 	// stop it again via the apiserver mock
-	podListWatch.Delete(pod, true) // notify watchers
+	lt.podListWatch.Delete(pod, true) // notify watchers
 
 	// and wait for the driver killTask call with the correct TaskId
 	select {
 	case <-killTaskCalled:
 		// report back that the task is finished
-		testScheduler.StatusUpdate(mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_FINISHED))
+		lt.scheduler.StatusUpdate(lt.mockDriver, newTaskStatusForTask(launchedTask, mesos.TaskState_TASK_FINISHED))
 
 	case <-time.After(5 * time.Second):
 		t.Fatal("timed out waiting for KillTask")
@@ -550,21 +506,21 @@ func TestPlugin_LifeCycle(t *testing.T) {
 	// - with different states on the apiserver
 
 	failPodFromExecutor := func(task *mesos.TaskInfo) {
-		beforePodLookups := testApiServer.Stats(pod.Name)
+		beforePodLookups := lt.apiServer.Stats(pod.Name)
 		status := newTaskStatusForTask(task, mesos.TaskState_TASK_FAILED)
 		message := messages.CreateBindingFailure
 		status.Message = &message
-		testScheduler.StatusUpdate(mockDriver, status)
+		lt.scheduler.StatusUpdate(lt.mockDriver, status)
 
 		// wait until pod is looked up at the apiserver
 		assertext.EventuallyTrue(t, time.Second, func() bool {
-			return testApiServer.Stats(pod.Name) == beforePodLookups+1
+			return lt.apiServer.Stats(pod.Name) == beforePodLookups+1
 		}, "expect that reconcilePod will access apiserver for pod %v", pod.Name)
 	}
 
 	// 1. with pod deleted from the apiserver
 	pod, launchedTask = startPod(offers1)
-	podListWatch.Delete(pod, false) // not notifying the watchers
+	lt.podListWatch.Delete(pod, false) // not notifying the watchers
 	failPodFromExecutor(launchedTask)
 
 	// 2. with pod still on the apiserver, not bound
@@ -574,17 +530,51 @@ func TestPlugin_LifeCycle(t *testing.T) {
 	// 3. with pod still on the apiserver, bound i.e. host!=""
 	pod, launchedTask = startPod(offers1)
 	pod.Spec.NodeName = *offers1[0].Hostname
-	podListWatch.Modify(pod, false) // not notifying the watchers
+	lt.podListWatch.Modify(pod, false) // not notifying the watchers
 	failPodFromExecutor(launchedTask)
 
 	// 4. with pod still on the apiserver, bound i.e. host!="", notified via ListWatch
 	pod, launchedTask = startPod(offers1)
 	pod.Spec.NodeName = *offers1[0].Hostname
-	podListWatch.Modify(pod, true) // notifying the watchers
-	time.Sleep(time.Second / 2)
+	lt.podListWatch.Modify(pod, true) // notifying the watchers
 	failPodFromExecutor(launchedTask)
 }
 
+// TestPlugin_LifeCycle_UnusedOffersDeclined guards against a single
+// scheduling attempt leaking every offer but the one it launches: when
+// ResourceOffers hands the scheduler more than one usable offer in a batch,
+// only one of them is ever consumed by scheduleFunc, and the rest must be
+// declined back to Mesos rather than silently forgotten.
+func TestPlugin_LifeCycle_UnusedOffersDeclined(t *testing.T) {
+	lt := newLifecycleTest(t)
+	defer lt.Close()
+	assert := lt.assert
+
+	launchedTasks := lt.Start()
+	defer lt.End()
+
+	offers := []*mesos.Offer{NewTestOffer(1), NewTestOffer(2)}
+	for _, offer := range offers {
+		lt.apiServer.WaitForNode(t, *offer.Hostname)
+	}
+
+	pod1 := NewTestPod(1)
+	lt.podListWatch.Add(pod1, true) // notify watchers
+
+	lt.scheduler.ResourceOffers(lt.mockDriver, offers)
+	assert.EventWithReason(lt.eventObs, controller.Scheduled)
+
+	select {
+	case <-launchedTasks:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for launchTasks call")
+	}
+
+	// exactly one offer was launched; the other must have been declined,
+	// not leaked.
+	lt.mockDriver.AssertNumberOfCalls(t, "DeclineOffer", 1)
+}
+
 func TestDeleteOne_NonexistentPod(t *testing.T) {
 	assert := assert.New(t)
 	obj := &MockScheduler{}
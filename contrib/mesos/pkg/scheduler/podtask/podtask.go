@@ -0,0 +1,142 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podtask tracks the lifecycle of a Kubernetes pod that has been
+// scheduled as a Mesos task, from the moment it is offered a slot through
+// to launch, running and termination.
+package podtask
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	mesos "github.com/mesos/mesos-go/mesosproto"
+)
+
+// State is the task's position in its lifecycle.
+type State int
+
+const (
+	Created State = iota
+	Launched
+	Running
+	Finished
+	Deleted
+)
+
+// T pairs a Kubernetes pod with the Mesos task that was created to run it.
+type T struct {
+	ID       string
+	Pod      api.Pod
+	Executor *mesos.ExecutorInfo
+	State    State
+
+	podKey string
+}
+
+// Set transitions the task to the given state.
+func (t *T) Set(state State) {
+	t.State = state
+}
+
+// podID returns the namespace/name key that identifies the pod backing t.
+func podID(pod *api.Pod) string {
+	return fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+}
+
+// New creates a not-yet-registered task for pod, to be launched via executor.
+func New(ctx api.Context, id string, pod api.Pod, executor *mesos.ExecutorInfo) *T {
+	return &T{
+		ID:       id,
+		Pod:      pod,
+		Executor: executor,
+		State:    Created,
+		podKey:   podID(&pod),
+	}
+}
+
+// Registry tracks in-flight tasks by task ID and by the pod they back.
+type Registry interface {
+	Register(task *T) (*T, error)
+	Update(task *T) error
+	Get(taskID string) (*T, bool)
+	ForPod(podID string) (*T, bool)
+	Delete(taskID string)
+}
+
+type inMemoryRegistry struct {
+	lock  sync.Mutex
+	tasks map[string]*T
+}
+
+// NewInMemoryRegistry returns a Registry backed by an in-process map, which
+// is all the real scheduler needs since it is itself the single source of
+// truth for task state.
+func NewInMemoryRegistry() Registry {
+	return &inMemoryRegistry{
+		tasks: map[string]*T{},
+	}
+}
+
+func (r *inMemoryRegistry) Register(task *T) (*T, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, exists := r.tasks[task.ID]; exists {
+		return nil, fmt.Errorf("task already registered: %v", task.ID)
+	}
+	r.tasks[task.ID] = task
+	return task, nil
+}
+
+func (r *inMemoryRegistry) Update(task *T) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, exists := r.tasks[task.ID]; !exists {
+		return fmt.Errorf("no such task: %v", task.ID)
+	}
+	r.tasks[task.ID] = task
+	return nil
+}
+
+func (r *inMemoryRegistry) Get(taskID string) (*T, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	task, ok := r.tasks[taskID]
+	return task, ok
+}
+
+func (r *inMemoryRegistry) ForPod(podID string) (*T, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, task := range r.tasks {
+		if task.podKey == podID {
+			return task, true
+		}
+	}
+	return nil, false
+}
+
+func (r *inMemoryRegistry) Delete(taskID string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.tasks, taskID)
+}
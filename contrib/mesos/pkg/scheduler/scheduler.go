@@ -0,0 +1,320 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/cache"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
+
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/executor/messages"
+	schedcfg "github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/config"
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/controller"
+	"github.com/GoogleCloudPlatform/kubernetes/contrib/mesos/pkg/scheduler/podtask"
+	log "github.com/golang/glog"
+	mesos "github.com/mesos/mesos-go/mesosproto"
+	util "github.com/mesos/mesos-go/mesosutil"
+	bindings "github.com/mesos/mesos-go/scheduler"
+)
+
+// ScheduleFunc picks an offer to run task against from the given set of
+// currently available offers, or returns an error if none will do.
+type ScheduleFunc func(offers []*mesos.Offer, task *podtask.T) (*mesos.Offer, error)
+
+// FCFSScheduleFunc satisfies the task with the very first offer on hand;
+// it is the simplest possible ScheduleFunc and the one used outside of
+// tests today.
+func FCFSScheduleFunc(offers []*mesos.Offer, task *podtask.T) (*mesos.Offer, error) {
+	if len(offers) == 0 {
+		return nil, fmt.Errorf("no offers available to schedule task %v", task.ID)
+	}
+	return offers[0], nil
+}
+
+// Config bundles everything needed to construct a KubernetesScheduler.
+type Config struct {
+	Executor     *mesos.ExecutorInfo
+	Client       *client.Client
+	ScheduleFunc ScheduleFunc
+	Schedcfg     schedcfg.Config
+}
+
+// PluginConfig is handed to NewPlugin; it carries the wiring a Plugin needs
+// in order to watch pods and report events back to the apiserver.
+type PluginConfig struct {
+	Scheduler     *KubernetesScheduler
+	Client        *client.Client
+	Recorder      record.EventRecorder
+	PodsListWatch *cache.ListWatch
+	Mux           *http.ServeMux
+}
+
+// KubernetesScheduler implements bindings.Scheduler on behalf of the
+// Kubernetes Mesos framework: it turns Mesos offers into launched pods and
+// keeps the Kubernetes task registry in sync with what Mesos reports back.
+type KubernetesScheduler struct {
+	client       *client.Client
+	executor     *mesos.ExecutorInfo
+	scheduleFunc ScheduleFunc
+	schedcfg     schedcfg.Config
+
+	lock   sync.Mutex
+	offers *offerRegistry
+	tasksR podtask.Registry
+	nodes  *nodeStore
+
+	frameworkID *mesos.FrameworkID
+	masterInfo  *mesos.MasterInfo
+	driver      bindings.SchedulerDriver
+	recorder    record.EventRecorder
+}
+
+// New constructs a KubernetesScheduler from the given Config.
+func New(c Config) *KubernetesScheduler {
+	return &KubernetesScheduler{
+		client:       c.Client,
+		executor:     c.Executor,
+		scheduleFunc: c.ScheduleFunc,
+		schedcfg:     c.Schedcfg,
+		offers:       newOfferRegistry(),
+		tasksR:       podtask.NewInMemoryRegistry(),
+		nodes:        newNodeStore(),
+	}
+}
+
+// tasks exposes the task registry to the plugin's deleter/queuer.
+func (k *KubernetesScheduler) tasks() podtask.Registry {
+	return k.tasksR
+}
+
+// killTask asks Mesos to kill the task backing taskID.
+func (k *KubernetesScheduler) killTask(taskID string) error {
+	task, ok := k.tasksR.Get(taskID)
+	if !ok {
+		return fmt.Errorf("no such task: %v", taskID)
+	}
+	task.Set(podtask.Deleted)
+	if err := k.tasksR.Update(task); err != nil {
+		return err
+	}
+	if k.driver == nil {
+		return fmt.Errorf("no driver available to kill task %v", taskID)
+	}
+	_, err := k.driver.KillTask(util.NewTaskID(taskID))
+	if err == nil && k.recorder != nil {
+		k.recorder.Eventf(&task.Pod, controller.Killed, "killed task %v", taskID)
+	}
+	return err
+}
+
+// NewPluginConfig assembles a PluginConfig from the scheduler's own wiring
+// plus the caller-supplied pod ListWatch and mux.
+func (k *KubernetesScheduler) NewPluginConfig(terminate <-chan struct{}, mux *http.ServeMux, podsListWatch *cache.ListWatch) *PluginConfig {
+	return &PluginConfig{
+		Scheduler:     k,
+		Client:        k.client,
+		Recorder:      nil,
+		PodsListWatch: podsListWatch,
+		Mux:           mux,
+	}
+}
+
+// Init wires the scheduler up to the rest of the framework once a plugin
+// has been created for it: it starts watching /api/v1/nodes so that
+// ResourceOffers can gate placement on node registration, and starts
+// expiring offers that have sat unused for longer than Schedcfg.OfferTTL.
+func (k *KubernetesScheduler) Init(master <-chan string, plugin *Plugin, mux *http.ServeMux) error {
+	if k.client == nil {
+		return fmt.Errorf("cannot Init scheduler without a client")
+	}
+	if plugin != nil && plugin.config != nil {
+		k.recorder = plugin.config.Recorder
+	}
+	k.nodes.watch(k.client)
+	k.expireOffers()
+	return nil
+}
+
+// expireOffers starts a goroutine that periodically declines and forgets
+// offers older than Schedcfg.OfferTTL, at the interval set by
+// Schedcfg.ListenerDelay. It never terminates; the scheduler is expected to
+// live for the lifetime of the process.
+func (k *KubernetesScheduler) expireOffers() {
+	go func() {
+		for {
+			time.Sleep(k.schedcfg.ListenerDelay)
+			k.lock.Lock()
+			driver := k.driver
+			k.lock.Unlock()
+			k.offers.expireOlderThan(k.schedcfg.OfferTTL, driver)
+		}
+	}()
+}
+
+// Registered is called by the Mesos driver once the framework has
+// successfully registered with the master.
+func (k *KubernetesScheduler) Registered(driver bindings.SchedulerDriver, frameworkID *mesos.FrameworkID, masterInfo *mesos.MasterInfo) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.frameworkID = frameworkID
+	k.masterInfo = masterInfo
+	k.driver = driver
+	log.Infof("scheduler registered with framework ID %v, master %v", frameworkID.GetValue(), masterInfo)
+}
+
+// Reregistered is called after a master failover once the framework has
+// re-established its registration with the new master.
+func (k *KubernetesScheduler) Reregistered(driver bindings.SchedulerDriver, masterInfo *mesos.MasterInfo) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	k.masterInfo = masterInfo
+	k.driver = driver
+	log.Infof("scheduler reregistered with master %v", masterInfo)
+}
+
+// Disconnected is called when the driver loses its connection to the master.
+func (k *KubernetesScheduler) Disconnected(driver bindings.SchedulerDriver) {
+	log.Info("scheduler disconnected from master")
+}
+
+// ResourceOffers hands every offer either to the offer registry, if it
+// comes from a host whose Node has already registered with the apiserver,
+// or back to Mesos via DeclineOffer otherwise.
+func (k *KubernetesScheduler) ResourceOffers(driver bindings.SchedulerDriver, offers []*mesos.Offer) {
+	if driver != nil {
+		k.lock.Lock()
+		k.driver = driver
+		k.lock.Unlock()
+	}
+	for _, offer := range offers {
+		hostname := offer.GetHostname()
+		if !k.nodes.isRegistered(hostname) {
+			log.V(2).Infof("declining offer %v: no registered node for hostname %v", offer.Id.GetValue(), hostname)
+			driver.DeclineOffer(offer.Id, &mesos.Filters{})
+			k.offers.noteUnregisteredNodeDecline()
+			continue
+		}
+		k.offers.add(offer)
+	}
+}
+
+// OfferRescinded removes a previously-offered resource from the registry.
+func (k *KubernetesScheduler) OfferRescinded(driver bindings.SchedulerDriver, offerID *mesos.OfferID) {
+	k.offers.remove(offerID.GetValue())
+}
+
+// StatusUpdate processes a task status change reported by Mesos.
+func (k *KubernetesScheduler) StatusUpdate(driver bindings.SchedulerDriver, status *mesos.TaskStatus) {
+	taskID := status.TaskId.GetValue()
+	task, ok := k.tasksR.Get(taskID)
+	if !ok {
+		log.Infof("status update for unknown task %v: %v", taskID, status.State)
+		return
+	}
+
+	switch status.GetState() {
+	case mesos.TaskState_TASK_RUNNING:
+		task.Set(podtask.Running)
+		k.tasksR.Update(task)
+	case mesos.TaskState_TASK_FINISHED:
+		task.Set(podtask.Finished)
+		k.tasksR.Update(task)
+	case mesos.TaskState_TASK_LOST, mesos.TaskState_TASK_FAILED, mesos.TaskState_TASK_KILLED:
+		k.handleTerminalStatus(driver, task, status)
+	}
+}
+
+// handleTerminalStatus reacts to a task that Mesos will not run any
+// further: on a lost task it tells the executor to give up on it, and on a
+// bind failure reported by the executor it reconciles the pod's state
+// against the apiserver so it can be rescheduled.
+func (k *KubernetesScheduler) handleTerminalStatus(driver bindings.SchedulerDriver, task *podtask.T, status *mesos.TaskStatus) {
+	task.Set(podtask.Deleted)
+	k.tasksR.Update(task)
+
+	if status.GetState() == mesos.TaskState_TASK_LOST {
+		driver.SendFrameworkMessage(task.Executor.ExecutorId, status.SlaveId, messages.TaskLost)
+		if k.recorder != nil {
+			k.recorder.Eventf(&task.Pod, controller.Killed, "task %v was lost", task.ID)
+		}
+		return
+	}
+
+	if status.GetMessage() == "" {
+		return
+	}
+
+	if status.GetMessage() == messages.CreateBindingFailure && k.recorder != nil {
+		k.recorder.Eventf(&task.Pod, controller.FailedBinding, "%v", status.GetMessage())
+	}
+
+	k.reconcilePod(task)
+	if k.recorder != nil {
+		k.recorder.Eventf(&task.Pod, controller.Reconciled, "reconciled pod %v/%v after terminal status", task.Pod.Namespace, task.Pod.Name)
+	}
+}
+
+// reconcilePod looks the task's pod up on the apiserver, which is how the
+// real scheduler learns whether it still needs to be (re)scheduled.
+func (k *KubernetesScheduler) reconcilePod(task *podtask.T) {
+	_, err := k.client.Pods(task.Pod.Namespace).Get(task.Pod.Name)
+	if err != nil {
+		log.V(2).Infof("pod %v/%v no longer exists on the apiserver: %v", task.Pod.Namespace, task.Pod.Name, err)
+	}
+}
+
+// FrameworkMessage handles an executor-originated message.
+func (k *KubernetesScheduler) FrameworkMessage(driver bindings.SchedulerDriver, executorID *mesos.ExecutorID, slaveID *mesos.SlaveID, data string) {
+	log.Infof("received framework message from executor %v: %v", executorID.GetValue(), data)
+}
+
+// SlaveLost marks the loss of a Mesos slave.
+func (k *KubernetesScheduler) SlaveLost(driver bindings.SchedulerDriver, slaveID *mesos.SlaveID) {
+	log.Infof("slave lost: %v", slaveID.GetValue())
+}
+
+// ExecutorLost marks the loss of an executor on a slave.
+func (k *KubernetesScheduler) ExecutorLost(driver bindings.SchedulerDriver, executorID *mesos.ExecutorID, slaveID *mesos.SlaveID, status int) {
+	log.Infof("executor lost: %v on slave %v", executorID.GetValue(), slaveID.GetValue())
+}
+
+// Error surfaces a fatal error reported by the driver.
+func (k *KubernetesScheduler) Error(driver bindings.SchedulerDriver, err string) {
+	log.Errorf("scheduler error: %v", err)
+}
+
+// buildTaskInfo constructs the Mesos TaskInfo used to launch task on offer.
+func buildTaskInfo(task *podtask.T, offer *mesos.Offer) *mesos.TaskInfo {
+	taskID := util.NewTaskID(task.ID)
+	return &mesos.TaskInfo{
+		Name:     &task.Pod.Name,
+		TaskId:   taskID,
+		SlaveId:  offer.SlaveId,
+		Executor: task.Executor,
+		Resources: []*mesos.Resource{
+			util.NewScalarResource("cpus", 0.25),
+			util.NewScalarResource("mem", 64),
+		},
+	}
+}